@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/distribution/reference"
+)
+
+// cgroupContainerRef is a container ID parsed straight out of
+// /proc/<pid>/cgroup, with the runtime that manages it if the cgroup
+// path names one.
+type cgroupContainerRef struct {
+	Runtime     string
+	ContainerID string
+}
+
+// cgroupV1LegacyRegexp matches the per-controller docker path used on
+// cgroup v1 hosts, e.g. "/docker/<64-hex>".
+var cgroupV1LegacyRegexp = regexp.MustCompile(`/docker/([0-9a-f]{64})`)
+
+// parseCgroupV1 extracts a container ID from the legacy cgroup v1
+// hierarchy, where each controller line ends in "/docker/<id>".
+func parseCgroupV1(content string) (cgroupContainerRef, bool) {
+	matches := cgroupV1LegacyRegexp.FindStringSubmatch(content)
+	if matches == nil {
+		return cgroupContainerRef{}, false
+	}
+
+	return cgroupContainerRef{Runtime: "docker", ContainerID: matches[1]}, true
+}
+
+// cgroupV2ScopeRegexp matches the systemd-managed container scope names
+// used under the cgroup v2 unified hierarchy (a single "0::/..." line),
+// e.g. "docker-<id>.scope" or "crio-<id>.scope".
+var cgroupV2ScopeRegexp = regexp.MustCompile(`(docker|crio|containerd|libpod|podman)-([0-9a-f]{64})\.scope`)
+
+// parseCgroupV2 extracts a runtime name and container ID from the
+// cgroup v2 unified hierarchy's systemd scope naming.
+func parseCgroupV2(content string) (cgroupContainerRef, bool) {
+	matches := cgroupV2ScopeRegexp.FindStringSubmatch(content)
+	if matches == nil {
+		return cgroupContainerRef{}, false
+	}
+
+	runtime := matches[1]
+	if runtime == "libpod" {
+		runtime = "podman"
+	}
+
+	return cgroupContainerRef{Runtime: runtime, ContainerID: matches[2]}, true
+}
+
+// containerRefFromCgroup reads /proc/<pid>/cgroup and tries the cgroup
+// v2 unified hierarchy first, then the cgroup v1 legacy layout. This is
+// pure string parsing with no runtime RPC involved.
+func containerRefFromCgroup(pid string) (cgroupContainerRef, bool) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%s/cgroup", pid))
+	if err != nil {
+		return cgroupContainerRef{}, false
+	}
+
+	if ref, found := parseCgroupV2(string(content)); found {
+		return ref, true
+	}
+
+	return parseCgroupV1(string(content))
+}
+
+// RuntimeBackend looks up container image/digest information for a
+// container ID or PID from one specific container runtime.
+type RuntimeBackend interface {
+	Name() string
+	LookupByContainerID(containerID string) (image, digest string, ok bool)
+	LookupByPid(pid string) (image, digest string, ok bool)
+}
+
+// ContainerResolver attributes a PID to a container image across
+// multiple runtimes and cgroup layouts. It tries the cheap, RPC-free
+// cgroup string parse first, then falls back to asking each configured
+// runtime backend directly. This covers cgroup v2 hosts (modern GitHub
+// Actions runners) and rootless Podman/Docker, where the Docker socket
+// alone is not the source of truth.
+type ContainerResolver struct {
+	backends []RuntimeBackend
+}
+
+// NewContainerResolver builds a resolver over the given runtime
+// backends, tried in order on a cgroup-parse miss.
+func NewContainerResolver(backends ...RuntimeBackend) *ContainerResolver {
+	return &ContainerResolver{backends: backends}
+}
+
+// Resolve returns the image and digest of the container hosting pid.
+func (r *ContainerResolver) Resolve(pid string) (image, digest string) {
+	if ref, found := containerRefFromCgroup(pid); found {
+		for _, backend := range r.backends {
+			if backend.Name() != ref.Runtime {
+				continue
+			}
+			if image, digest, ok := backend.LookupByContainerID(ref.ContainerID); ok {
+				return image, digest
+			}
+		}
+	}
+
+	for _, backend := range r.backends {
+		if image, digest, ok := backend.LookupByPid(pid); ok {
+			return image, digest
+		}
+	}
+
+	return "", ""
+}
+
+var containerResolver *ContainerResolver
+var containerResolverOnce sync.Once
+
+// getContainerResolver lazily builds the resolver from the Docker
+// watcher and a Podman backend, mirroring the lazy init pattern used
+// elsewhere in this package.
+func getContainerResolver() *ContainerResolver {
+	containerResolverOnce.Do(func() {
+		containerResolver = NewContainerResolver(getDockerWatcher(), newPodmanBackend())
+	})
+	return containerResolver
+}
+
+// Name implements RuntimeBackend for DockerWatcher.
+func (w *DockerWatcher) Name() string {
+	return "docker"
+}
+
+// LookupByContainerID implements RuntimeBackend for DockerWatcher using
+// the existing event-driven cache.
+func (w *DockerWatcher) LookupByContainerID(containerID string) (image, digest string, ok bool) {
+	w.mu.RLock()
+	info, found := w.containerInfo[containerID]
+	w.mu.RUnlock()
+	if !found {
+		return "", "", false
+	}
+
+	return info.Image, info.Digest, true
+}
+
+// LookupByPid implements RuntimeBackend for DockerWatcher.
+func (w *DockerWatcher) LookupByPid(pid string) (image, digest string, ok bool) {
+	image, digest = w.lookupByPid(pid)
+	return image, digest, image != ""
+}
+
+// PodmanBackend resolves containers via the rootless Podman REST API,
+// which the agent reaches over the user's libpod socket rather than a
+// shared Docker daemon.
+type PodmanBackend struct {
+	socketPath string
+	httpClient *http.Client
+
+	mu           sync.RWMutex
+	imageDigests map[string]string // image ref -> sha256 digest
+}
+
+func newPodmanBackend() *PodmanBackend {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	socketPath := filepath.Join(runtimeDir, "podman", "podman.sock")
+
+	return &PodmanBackend{
+		socketPath:   socketPath,
+		imageDigests: make(map[string]string),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return net.Dial("unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+func (p *PodmanBackend) Name() string {
+	return "podman"
+}
+
+// podmanContainer is the subset of the libpod-compatible
+// /containers/json response this backend needs.
+type podmanContainer struct {
+	ID    string `json:"Id"`
+	Image string `json:"Image"`
+	Pid   int    `json:"Pid"`
+}
+
+func (p *PodmanBackend) listContainers() ([]podmanContainer, error) {
+	resp, err := p.httpClient.Get("http://podman/containers/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var containers []podmanContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+
+	return containers, nil
+}
+
+// LookupByContainerID implements RuntimeBackend for PodmanBackend.
+func (p *PodmanBackend) LookupByContainerID(containerID string) (image, digest string, ok bool) {
+	containers, err := p.listContainers()
+	if err != nil {
+		return "", "", false
+	}
+
+	for _, c := range containers {
+		if strings.HasPrefix(c.ID, containerID) {
+			return c.Image, p.resolveImageDigest(c.Image), true
+		}
+	}
+
+	return "", "", false
+}
+
+// LookupByPid implements RuntimeBackend for PodmanBackend.
+func (p *PodmanBackend) LookupByPid(pid string) (image, digest string, ok bool) {
+	containers, err := p.listContainers()
+	if err != nil {
+		return "", "", false
+	}
+
+	for _, c := range containers {
+		if fmt.Sprintf("%d", c.Pid) == pid {
+			return c.Image, p.resolveImageDigest(c.Image), true
+		}
+	}
+
+	return "", "", false
+}
+
+// podmanImageInspect is the subset of the libpod-compatible
+// /images/{name}/json response this backend needs.
+type podmanImageInspect struct {
+	RepoDigests []string `json:"RepoDigests"`
+	Id          string   `json:"Id"`
+}
+
+// resolveImageDigest returns the sha256 digest for imageRef, the same
+// way DockerWatcher.resolveImageDigest does for the Docker backend:
+// prefer the registry-pinned RepoDigests entry, falling back to the
+// local image ID, and cache per image so repeated containers of the
+// same image only inspect it once. If the image cannot be inspected,
+// imageRef itself (without a "sha256:" prefix) is cached instead.
+func (p *PodmanBackend) resolveImageDigest(imageRef string) string {
+	p.mu.RLock()
+	digest, found := p.imageDigests[imageRef]
+	p.mu.RUnlock()
+	if found {
+		return digest
+	}
+
+	digest = strings.TrimPrefix(imageRef, "sha256:")
+
+	resp, err := p.httpClient.Get(fmt.Sprintf("http://podman/images/%s/json", imageRef))
+	if err == nil {
+		defer resp.Body.Close()
+
+		var inspect podmanImageInspect
+		if json.NewDecoder(resp.Body).Decode(&inspect) == nil {
+			if inspect.Id != "" {
+				digest = strings.TrimPrefix(inspect.Id, "sha256:")
+			}
+			if len(inspect.RepoDigests) > 0 {
+				if parsed, err := reference.Parse(inspect.RepoDigests[0]); err == nil {
+					if canonical, ok := parsed.(reference.Canonical); ok {
+						digest = canonical.Digest().Encoded()
+					}
+				}
+			}
+		}
+	}
+
+	p.mu.Lock()
+	p.imageDigests[imageRef] = digest
+	p.mu.Unlock()
+
+	return digest
+}
@@ -0,0 +1,116 @@
+package main
+
+import "testing"
+
+func TestParseCgroupV1(t *testing.T) {
+	content := "12:pids:/docker/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\n" +
+		"11:cpu,cpuacct:/docker/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\n"
+
+	ref, ok := parseCgroupV1(content)
+	if !ok {
+		t.Fatalf("parseCgroupV1() ok = false, want true")
+	}
+	if ref.Runtime != "docker" {
+		t.Errorf("Runtime = %q, want %q", ref.Runtime, "docker")
+	}
+	if ref.ContainerID != "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" {
+		t.Errorf("ContainerID = %q", ref.ContainerID)
+	}
+}
+
+func TestParseCgroupV2(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantRuntime string
+		wantOk      bool
+	}{
+		{
+			name:        "docker scope",
+			content:     "0::/system.slice/docker-bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb.scope\n",
+			wantRuntime: "docker",
+			wantOk:      true,
+		},
+		{
+			name:        "rootless podman scope reports as libpod",
+			content:     "0::/user.slice/user-1000.slice/user@1000.service/libpod-cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc.scope\n",
+			wantRuntime: "podman",
+			wantOk:      true,
+		},
+		{
+			name:    "no scope",
+			content: "0::/user.slice/user-1000.slice/session-1.scope\n",
+			wantOk:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, ok := parseCgroupV2(tt.content)
+			if ok != tt.wantOk {
+				t.Fatalf("parseCgroupV2() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if ref.Runtime != tt.wantRuntime {
+				t.Errorf("Runtime = %q, want %q", ref.Runtime, tt.wantRuntime)
+			}
+		})
+	}
+}
+
+// fakeRuntimeBackend is a stub RuntimeBackend for exercising
+// ContainerResolver.Resolve's fallback order without a real daemon.
+type fakeRuntimeBackend struct {
+	name          string
+	byContainerID map[string][2]string
+	byPid         map[string][2]string
+}
+
+func (b *fakeRuntimeBackend) Name() string { return b.name }
+
+func (b *fakeRuntimeBackend) LookupByContainerID(containerID string) (image, digest string, ok bool) {
+	info, found := b.byContainerID[containerID]
+	return info[0], info[1], found
+}
+
+func (b *fakeRuntimeBackend) LookupByPid(pid string) (image, digest string, ok bool) {
+	info, found := b.byPid[pid]
+	return info[0], info[1], found
+}
+
+func TestContainerResolverTriesBackendsInOrder(t *testing.T) {
+	docker := &fakeRuntimeBackend{
+		name:  "docker",
+		byPid: map[string][2]string{"4242": {"docker-image:latest", "deadbeef"}},
+	}
+	podman := &fakeRuntimeBackend{
+		name:  "podman",
+		byPid: map[string][2]string{"4242": {"podman-image:latest", "cafebabe"}},
+	}
+
+	image, digest := NewContainerResolver(docker, podman).Resolve("4242")
+	if image != "docker-image:latest" || digest != "deadbeef" {
+		t.Errorf("Resolve() = (%q, %q), want the first backend's match (%q, %q)", image, digest, "docker-image:latest", "deadbeef")
+	}
+
+	if image, digest := NewContainerResolver(podman, docker).Resolve("4242"); image != "podman-image:latest" || digest != "cafebabe" {
+		t.Errorf("Resolve() = (%q, %q), want the first backend's match (%q, %q)", image, digest, "podman-image:latest", "cafebabe")
+	}
+}
+
+func TestContainerResolverFallsBackToBackendsOnCgroupMiss(t *testing.T) {
+	docker := &fakeRuntimeBackend{name: "docker"}
+	podman := &fakeRuntimeBackend{
+		name:  "podman",
+		byPid: map[string][2]string{"4242": {"rootless-image:latest", "cafebabe"}},
+	}
+
+	resolver := NewContainerResolver(docker, podman)
+
+	image, digest := resolver.Resolve("4242")
+	if image != "rootless-image:latest" || digest != "cafebabe" {
+		t.Errorf("Resolve() = (%q, %q), want (%q, %q)", image, digest, "rootless-image:latest", "cafebabe")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DependencyDetector recognizes a file event as belonging to a specific
+// package manager's install location and extracts the package it
+// pulled in. Implementations are registered in dependencyDetectors and
+// tried in order by handleFileEvent.
+type DependencyDetector interface {
+	// Match returns the file type tag ("Dependencies"), the ecosystem
+	// name, and the package identifier for event, or ok=false if this
+	// detector doesn't recognize it. The identifier is taken as it
+	// appears in the install path (e.g. "lodash", "rack-2.2.3",
+	// "github.com/gorilla/mux@v1.8.0"), so it carries a version only
+	// where the path does, in that ecosystem's own layout.
+	Match(event *Event) (fileType string, ecosystem string, pkg string, ok bool)
+}
+
+// dependencyDetectors is the ordered set of detectors handleFileEvent
+// consults for every file event that isn't already classified as
+// "Source Code". Order matters only in that the first match wins.
+var dependencyDetectors = []DependencyDetector{
+	npmDetector{},
+	pipDetector{},
+	goModuleDetector{},
+	mavenDetector{},
+	rubyGemsDetector{},
+	cargoDetector{},
+	nugetDetector{},
+}
+
+type npmDetector struct{}
+
+var npmPathRegexp = regexp.MustCompile(`/node_modules/((?:@[^/]+/)?[^/]+)/`)
+
+func (npmDetector) Match(event *Event) (string, string, string, bool) {
+	matches := npmPathRegexp.FindStringSubmatch(event.FileName)
+	if matches == nil {
+		return "", "", "", false
+	}
+
+	return "Dependencies", "npm", matches[1], true
+}
+
+type pipDetector struct{}
+
+var pipSitePackagesRegexp = regexp.MustCompile(`site-packages/([^/]+)/`)
+var pipDistInfoRegexp = regexp.MustCompile(`([^/]+)\.dist-info/RECORD$`)
+
+func (pipDetector) Match(event *Event) (string, string, string, bool) {
+	if matches := pipSitePackagesRegexp.FindStringSubmatch(event.FileName); matches != nil {
+		return "Dependencies", "pip", matches[1], true
+	}
+
+	if matches := pipDistInfoRegexp.FindStringSubmatch(event.FileName); matches != nil {
+		return "Dependencies", "pip", matches[1], true
+	}
+
+	return "", "", "", false
+}
+
+type goModuleDetector struct{}
+
+var goModCacheRegexp = regexp.MustCompile(`pkg/mod/(.+@v[^/]+)`)
+
+func (goModuleDetector) Match(event *Event) (string, string, string, bool) {
+	if matches := goModCacheRegexp.FindStringSubmatch(event.FileName); matches != nil {
+		return "Dependencies", "go", matches[1], true
+	}
+
+	if strings.HasSuffix(event.FileName, "go.sum") {
+		return "Dependencies", "go", "go.sum", true
+	}
+
+	return "", "", "", false
+}
+
+type mavenDetector struct{}
+
+var mavenRepoRegexp = regexp.MustCompile(`\.m2/repository/(.+/[^/]+/[^/]+)/[^/]+\.(jar|pom)$`)
+
+func (mavenDetector) Match(event *Event) (string, string, string, bool) {
+	matches := mavenRepoRegexp.FindStringSubmatch(event.FileName)
+	if matches == nil {
+		return "", "", "", false
+	}
+
+	return "Dependencies", "maven", matches[1], true
+}
+
+type rubyGemsDetector struct{}
+
+var rubyGemsRegexp = regexp.MustCompile(`gems/([^/]+-[0-9][^/]*)/`)
+
+func (rubyGemsDetector) Match(event *Event) (string, string, string, bool) {
+	matches := rubyGemsRegexp.FindStringSubmatch(event.FileName)
+	if matches == nil {
+		return "", "", "", false
+	}
+
+	return "Dependencies", "rubygems", matches[1], true
+}
+
+type cargoDetector struct{}
+
+var cargoRegistryRegexp = regexp.MustCompile(`\.cargo/registry/src/[^/]+/([^/]+)/`)
+
+func (cargoDetector) Match(event *Event) (string, string, string, bool) {
+	matches := cargoRegistryRegexp.FindStringSubmatch(event.FileName)
+	if matches == nil {
+		return "", "", "", false
+	}
+
+	return "Dependencies", "cargo", matches[1], true
+}
+
+type nugetDetector struct{}
+
+var nugetPackagesRegexp = regexp.MustCompile(`\.nuget/packages/([^/]+/[^/]+)/`)
+
+func (nugetDetector) Match(event *Event) (string, string, string, bool) {
+	matches := nugetPackagesRegexp.FindStringSubmatch(event.FileName)
+	if matches == nil {
+		return "", "", "", false
+	}
+
+	return "Dependencies", "nuget", matches[1], true
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestDependencyDetectors(t *testing.T) {
+	tests := []struct {
+		name          string
+		detector      DependencyDetector
+		fileName      string
+		wantEcosystem string
+		wantPkg       string
+		wantOk        bool
+	}{
+		{"npm package", npmDetector{}, "/app/node_modules/lodash/index.js", "npm", "lodash", true},
+		{"npm scoped package", npmDetector{}, "/app/node_modules/@babel/core/lib/index.js", "npm", "@babel/core", true},
+		{"npm no match", npmDetector{}, "/app/src/index.js", "", "", false},
+
+		{"pip site-packages", pipDetector{}, "/usr/lib/python3.10/site-packages/requests/api.py", "pip", "requests", true},
+		{"pip dist-info", pipDetector{}, "/home/user/.venv/lib/requests-2.31.0.dist-info/RECORD", "pip", "requests-2.31.0", true},
+		{"pip no match", pipDetector{}, "/usr/lib/python3.10/os.py", "", "", false},
+
+		{"go module cache", goModuleDetector{}, "/root/go/pkg/mod/github.com/gorilla/mux@v1.8.0/mux.go", "go", "github.com/gorilla/mux@v1.8.0", true},
+		{"go.sum read", goModuleDetector{}, "/home/user/project/go.sum", "go", "go.sum", true},
+		{"go no match", goModuleDetector{}, "/root/go/src/main.go", "", "", false},
+
+		{"maven repository", mavenDetector{}, "/root/.m2/repository/org/apache/commons/commons-lang3/3.12.0/commons-lang3-3.12.0.jar", "maven", "org/apache/commons/commons-lang3/3.12.0", true},
+		{"maven no match", mavenDetector{}, "/root/.m2/settings.xml", "", "", false},
+
+		{"rubygems", rubyGemsDetector{}, "/var/lib/gems/3.0.0/gems/rack-2.2.3/lib/rack.rb", "rubygems", "rack-2.2.3", true},
+		{"rubygems no match", rubyGemsDetector{}, "/var/lib/gems/3.0.0/specifications/rack.gemspec", "", "", false},
+
+		{"cargo registry", cargoDetector{}, "/root/.cargo/registry/src/github.com-1ecc6299db9ec823/serde-1.0.190/src/lib.rs", "cargo", "serde-1.0.190", true},
+		{"cargo no match", cargoDetector{}, "/root/.cargo/config.toml", "", "", false},
+
+		{"nuget packages", nugetDetector{}, "/root/.nuget/packages/newtonsoft.json/13.0.3/lib/net6.0/Newtonsoft.Json.dll", "nuget", "newtonsoft.json/13.0.3", true},
+		{"nuget no match", nugetDetector{}, "/root/.nuget/NuGet.Config", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &Event{FileName: tt.fileName}
+			fileType, ecosystem, pkg, ok := tt.detector.Match(event)
+
+			if ok != tt.wantOk {
+				t.Fatalf("Match() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+
+			if fileType != "Dependencies" {
+				t.Errorf("Match() fileType = %q, want %q", fileType, "Dependencies")
+			}
+			if ecosystem != tt.wantEcosystem {
+				t.Errorf("Match() ecosystem = %q, want %q", ecosystem, tt.wantEcosystem)
+			}
+			if pkg != tt.wantPkg {
+				t.Errorf("Match() pkg = %q, want %q", pkg, tt.wantPkg)
+			}
+		})
+	}
+}
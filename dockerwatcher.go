@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+const (
+	eventStreamReconnectBaseDelay = 1 * time.Second
+	eventStreamReconnectMaxDelay  = 30 * time.Second
+)
+
+// dockerContainerInfo is the cached view of a running container that
+// DockerWatcher keeps up to date from the Docker events stream.
+type dockerContainerInfo struct {
+	Image   string
+	Digest  string
+	RootPID string
+}
+
+// DockerWatcher keeps an in-memory map of running containers up to date
+// by consuming the Docker events stream once, instead of opening a new
+// client and calling ContainerList/ContainerInspect on every network
+// event. GetContainerByPid becomes a cache lookup, falling back to the
+// slow ContainerList path only on a miss.
+type DockerWatcher struct {
+	cli *client.Client
+
+	mu             sync.RWMutex
+	containerInfo  map[string]dockerContainerInfo // containerID -> info
+	pidToContainer map[string]string              // rootPID -> containerID
+	imageDigests   map[string]string              // repo tag -> sha256 digest
+}
+
+var dockerWatcher *DockerWatcher
+var dockerWatcherOnce sync.Once
+
+// getDockerWatcher lazily starts the watcher on first use, the same
+// lazy-init pattern the subnet globals in isPrivateIPAddress use.
+func getDockerWatcher() *DockerWatcher {
+	dockerWatcherOnce.Do(func() {
+		dockerWatcher = newDockerWatcher()
+	})
+	return dockerWatcher
+}
+
+func newDockerWatcher() *DockerWatcher {
+	w := &DockerWatcher{
+		containerInfo:  make(map[string]dockerContainerInfo),
+		pidToContainer: make(map[string]string),
+		imageDigests:   make(map[string]string),
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return w
+	}
+	w.cli = cli
+
+	w.warmCache()
+	go w.watch()
+
+	return w
+}
+
+// warmCache does a single ContainerList call at startup so lookups
+// succeed for containers that were already running before the watcher
+// started consuming the event stream.
+func (w *DockerWatcher) warmCache() {
+	ctx := context.Background()
+	containers, err := w.cli.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return
+	}
+
+	for _, container := range containers {
+		w.recordContainer(ctx, container.ID)
+	}
+}
+
+// watch consumes the Docker events stream for the lifetime of the agent,
+// keeping containerInfo and pidToContainer current so GetContainerByPid
+// never has to make a Docker API call on the hot path. A stream error
+// (daemon not up yet at startup, a later daemon restart, ...) triggers
+// a resubscribe with exponential backoff rather than giving up, which
+// would otherwise silently reinstate the slow ContainerList path for
+// the rest of the process lifetime.
+func (w *DockerWatcher) watch() {
+	delay := eventStreamReconnectBaseDelay
+
+	for {
+		if w.watchOnce() {
+			delay = eventStreamReconnectBaseDelay
+			continue
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+		if delay > eventStreamReconnectMaxDelay {
+			delay = eventStreamReconnectMaxDelay
+		}
+	}
+}
+
+// watchOnce subscribes to the Docker events stream and consumes it
+// until the stream errors out or closes. It returns true if at least
+// one event was delivered, so watch only resets its backoff after a
+// subscription that was actually healthy for a while.
+func (w *DockerWatcher) watchOnce() bool {
+	ctx := context.Background()
+
+	eventFilter := filters.NewArgs()
+	eventFilter.Add("type", "container")
+	eventFilter.Add("event", "create")
+	eventFilter.Add("event", "start")
+	eventFilter.Add("event", "die")
+	eventFilter.Add("event", "destroy")
+
+	msgs, errs := w.cli.Events(ctx, types.EventsOptions{Filters: eventFilter})
+
+	received := false
+	for {
+		select {
+		case msg := <-msgs:
+			w.handleEvent(ctx, msg)
+			received = true
+		case err := <-errs:
+			if err != nil {
+				WriteLog(fmt.Sprintf("docker event stream error, reconnecting: %s", err))
+			}
+			return received
+		}
+	}
+}
+
+func (w *DockerWatcher) handleEvent(ctx context.Context, msg events.Message) {
+	switch msg.Action {
+	case "start":
+		w.recordContainer(ctx, msg.Actor.ID)
+	case "die", "destroy":
+		w.evictContainer(msg.Actor.ID)
+	}
+}
+
+// recordContainer inspects a container once and caches its image, image
+// digest, and root PID, keyed both by container ID and by root PID for
+// O(1) lookup.
+func (w *DockerWatcher) recordContainer(ctx context.Context, containerID string) {
+	json, err := w.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return
+	}
+
+	rootPID := fmt.Sprintf("%d", json.State.Pid)
+	digest := w.resolveImageDigest(ctx, json.Config.Image, json.Image)
+
+	w.mu.Lock()
+	w.containerInfo[containerID] = dockerContainerInfo{Image: json.Config.Image, Digest: digest, RootPID: rootPID}
+	w.pidToContainer[rootPID] = containerID
+	w.mu.Unlock()
+}
+
+// resolveImageDigest returns the sha256 digest for repoTag, preferring
+// the registry-pinned entry in RepoDigests over the local image ID
+// (fallbackImageID, already "sha256:<hex>") so the reported checksum is
+// verifiable against the origin registry. Results are cached per image
+// so repeated containers of the same image only inspect it once.
+func (w *DockerWatcher) resolveImageDigest(ctx context.Context, repoTag, fallbackImageID string) string {
+	w.mu.RLock()
+	digest, found := w.imageDigests[repoTag]
+	w.mu.RUnlock()
+	if found {
+		return digest
+	}
+
+	digest = strings.TrimPrefix(fallbackImageID, "sha256:")
+
+	inspect, _, err := w.cli.ImageInspectWithRaw(ctx, repoTag)
+	if err == nil && len(inspect.RepoDigests) > 0 {
+		if parsed, err := reference.Parse(inspect.RepoDigests[0]); err == nil {
+			if canonical, ok := parsed.(reference.Canonical); ok {
+				digest = canonical.Digest().Encoded()
+			}
+		}
+	}
+
+	w.mu.Lock()
+	w.imageDigests[repoTag] = digest
+	w.mu.Unlock()
+
+	return digest
+}
+
+func (w *DockerWatcher) evictContainer(containerID string) {
+	w.mu.Lock()
+	if info, found := w.containerInfo[containerID]; found {
+		delete(w.pidToContainer, info.RootPID)
+	}
+	delete(w.containerInfo, containerID)
+	w.mu.Unlock()
+}
+
+// lookupByPid returns the container image and image digest for pid,
+// preferring the cached rootPID -> containerID map and only falling
+// back to a live ContainerList call on a miss. Cgroup-string
+// attribution (v1 and v2) is ContainerResolver's job, tried once before
+// any backend is consulted; duplicating it here would let the two
+// parsers drift apart.
+func (w *DockerWatcher) lookupByPid(pid string) (image, digest string) {
+	w.mu.RLock()
+	if containerID, found := w.pidToContainer[pid]; found {
+		info := w.containerInfo[containerID]
+		w.mu.RUnlock()
+		return info.Image, info.Digest
+	}
+	w.mu.RUnlock()
+
+	return w.lookupSlow(pid)
+}
+
+// lookupSlow is the original per-call ContainerList/ContainerInspect
+// path, kept only as a fallback for the rare case a watch event was
+// missed (e.g. the agent started mid-container-lifecycle).
+func (w *DockerWatcher) lookupSlow(pid string) (image, digest string) {
+	if w.cli == nil {
+		return "", ""
+	}
+
+	ctx := context.Background()
+	containers, err := w.cli.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return "", ""
+	}
+
+	for _, container := range containers {
+		w.recordContainer(ctx, container.ID)
+
+		w.mu.RLock()
+		info, found := w.containerInfo[container.ID]
+		w.mu.RUnlock()
+		if found && info.RootPID == pid {
+			return info.Image, info.Digest
+		}
+	}
+
+	return "", ""
+}
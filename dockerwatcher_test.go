@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func newTestDockerWatcher() *DockerWatcher {
+	return &DockerWatcher{
+		containerInfo:  make(map[string]dockerContainerInfo),
+		pidToContainer: make(map[string]string),
+		imageDigests:   make(map[string]string),
+	}
+}
+
+func TestDockerWatcherLookupByPidCacheHit(t *testing.T) {
+	w := newTestDockerWatcher()
+	w.containerInfo["abc123"] = dockerContainerInfo{Image: "myimage:latest", Digest: "deadbeef", RootPID: "4242"}
+	w.pidToContainer["4242"] = "abc123"
+
+	image, digest := w.lookupByPid("4242")
+	if image != "myimage:latest" || digest != "deadbeef" {
+		t.Errorf("lookupByPid() = (%q, %q), want (%q, %q)", image, digest, "myimage:latest", "deadbeef")
+	}
+}
+
+func TestDockerWatcherLookupByPidMissWithoutClientReturnsEmpty(t *testing.T) {
+	w := newTestDockerWatcher()
+
+	image, digest := w.lookupByPid("9999")
+	if image != "" || digest != "" {
+		t.Errorf("lookupByPid() = (%q, %q), want empty values on a cache miss with no Docker client", image, digest)
+	}
+}
+
+func TestDockerWatcherEvictContainerClearsBothMaps(t *testing.T) {
+	w := newTestDockerWatcher()
+	w.containerInfo["abc123"] = dockerContainerInfo{Image: "myimage:latest", RootPID: "4242"}
+	w.pidToContainer["4242"] = "abc123"
+
+	w.evictContainer("abc123")
+
+	if _, found := w.containerInfo["abc123"]; found {
+		t.Errorf("containerInfo entry should be evicted")
+	}
+	if _, found := w.pidToContainer["4242"]; found {
+		t.Errorf("pidToContainer entry should be evicted")
+	}
+}
+
+func TestDockerWatcherRuntimeBackendName(t *testing.T) {
+	w := newTestDockerWatcher()
+	if w.Name() != "docker" {
+		t.Errorf("Name() = %q, want %q", w.Name(), "docker")
+	}
+}
+
+func TestDockerWatcherLookupByContainerID(t *testing.T) {
+	w := newTestDockerWatcher()
+	w.containerInfo["abc123"] = dockerContainerInfo{Image: "myimage:latest", Digest: "deadbeef", RootPID: "4242"}
+
+	image, digest, ok := w.LookupByContainerID("abc123")
+	if !ok || image != "myimage:latest" || digest != "deadbeef" {
+		t.Errorf("LookupByContainerID() = (%q, %q, %v), want (%q, %q, true)", image, digest, ok, "myimage:latest", "deadbeef")
+	}
+
+	if _, _, ok := w.LookupByContainerID("missing"); ok {
+		t.Errorf("LookupByContainerID() ok = true for an unknown container ID")
+	}
+}
@@ -1,26 +1,22 @@
 package main
 
 import (
-	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"sync"
-
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
 )
 
 type EventHandler struct {
 	CorrelationId        string
 	Repo                 string
 	ApiClient            *ApiClient
+	EventSink            *EventSink
 	DNSProxy             *DNSProxy
 	ProcessConnectionMap map[string]bool
 	ProcessFileMap       map[string]bool
@@ -34,8 +30,6 @@ type EventHandler struct {
 var classAPrivateSubnet, classBPrivateSubnet, classCPrivateSubnet, loopBackSubnet, ipv6LinkLocalSubnet, ipv6LocalSubnet *net.IPNet
 
 func (eventHandler *EventHandler) handleFileEvent(event *Event) {
-	eventHandler.fileMutex.Lock()
-
 	if !strings.HasPrefix(event.FileName, "/") {
 		event.FileName = path.Join(event.Path, event.FileName)
 	}
@@ -47,26 +41,43 @@ func (eventHandler *EventHandler) handleFileEvent(event *Event) {
 		writeDone()
 	}
 
-	_, found := eventHandler.ProcessFileMap[event.Pid]
+	// fileMutex guards only the dedupe maps below; the resulting
+	// ApiClient call is handed off to EventSink once the maps are
+	// updated, so a slow or unreachable API never blocks the file
+	// event pump.
+	eventHandler.fileMutex.Lock()
 	fileType := ""
-	if !found {
-		// TODO: Improve this logic to monitor dependencies across languages
-		if strings.Contains(event.FileName, "/node_modules/") && strings.HasSuffix(event.FileName, ".js") {
-			fileType = "Dependencies"
+	ecosystem := ""
+	pkg := ""
 
-		} else if strings.Contains(event.FileName, ".git/objects") {
+	if strings.Contains(event.FileName, ".git/objects") {
+		fileKey := fmt.Sprintf("%s:git:source", event.Pid)
+		if _, found := eventHandler.ProcessFileMap[fileKey]; !found {
 			fileType = "Source Code"
+			eventHandler.ProcessFileMap[fileKey] = true
 		}
+	}
+
+	if fileType == "" {
+		for _, detector := range dependencyDetectors {
+			detectedType, detectedEcosystem, detectedPkg, ok := detector.Match(event)
+			if !ok {
+				continue
+			}
 
-		if fileType != "" {
-			tool := *eventHandler.GetToolChain(event.PPid, event.Exe)
-			eventHandler.ApiClient.sendFileEvent(eventHandler.CorrelationId, eventHandler.Repo, fileType, event.Timestamp, tool)
-			eventHandler.ProcessFileMap[event.Pid] = true
+			fileKey := fmt.Sprintf("%s:%s:%s", event.Pid, detectedEcosystem, detectedPkg)
+			if _, found := eventHandler.ProcessFileMap[fileKey]; !found {
+				fileType = detectedType
+				ecosystem = detectedEcosystem
+				pkg = detectedPkg
+				eventHandler.ProcessFileMap[fileKey] = true
+			}
+			break
 		}
 	}
 
 	if isSourceCodeFile(event.FileName) && !isSyscallExcluded(event.Syscall) {
-		_, found = eventHandler.SourceCodeMap[event.FileName]
+		_, found := eventHandler.SourceCodeMap[event.FileName]
 		if !found {
 			eventHandler.SourceCodeMap[event.FileName] = append(eventHandler.SourceCodeMap[event.FileName], event)
 		}
@@ -86,6 +97,11 @@ func (eventHandler *EventHandler) handleFileEvent(event *Event) {
 	}
 
 	eventHandler.fileMutex.Unlock()
+
+	if fileType != "" {
+		tool := *eventHandler.GetToolChain(event.PPid, event.Exe)
+		eventHandler.EventSink.SendFileEvent(eventHandler.ApiClient, eventHandler.CorrelationId, eventHandler.Repo, fileType, ecosystem, pkg, event.Timestamp, tool)
+	}
 }
 
 func isSyscallExcluded(syscall string) bool {
@@ -122,36 +138,42 @@ func (eventHandler *EventHandler) handleProcessEvent(event *Event) {
 }
 
 func (eventHandler *EventHandler) handleNetworkEvent(event *Event) {
-	eventHandler.netMutex.Lock()
-
-	if !isPrivateIPAddress(event.IPAddress) &&
-		strings.Compare(event.IPAddress, "::1") != 0 &&
-		strings.Compare(event.IPAddress, AzureIPAddress) != 0 &&
-		strings.Compare(event.IPAddress, MetadataIPAddress) != 0 {
-
-		cacheKey := fmt.Sprintf("%s%s%s", event.Pid, event.IPAddress, event.Port)
+	if isPrivateIPAddress(event.IPAddress) ||
+		strings.Compare(event.IPAddress, "::1") == 0 ||
+		strings.Compare(event.IPAddress, AzureIPAddress) == 0 ||
+		strings.Compare(event.IPAddress, MetadataIPAddress) == 0 {
+		return
+	}
 
-		_, found := eventHandler.ProcessConnectionMap[cacheKey]
+	cacheKey := fmt.Sprintf("%s%s%s", event.Pid, event.IPAddress, event.Port)
 
-		if !found {
-			tool := Tool{}
-			image := GetContainerByPid(event.Pid)
-			if image == "" {
-				if event.Exe != "" {
-					tool = *eventHandler.GetToolChain(event.PPid, event.Exe)
-				}
+	// netMutex guards only ProcessConnectionMap; the ApiClient call is
+	// handed off to EventSink once the dedupe check passes, so a slow
+	// or unreachable API never blocks the network event pump.
+	eventHandler.netMutex.Lock()
+	_, found := eventHandler.ProcessConnectionMap[cacheKey]
+	if !found {
+		eventHandler.ProcessConnectionMap[cacheKey] = true
+	}
+	eventHandler.netMutex.Unlock()
 
-			} else {
-				tool = Tool{Name: image, SHA256: image} // TODO: Set container image checksum
-			}
+	if found {
+		return
+	}
 
-			reverseLookUp := eventHandler.DNSProxy.GetReverseIPLookup(event.IPAddress)
-			eventHandler.ApiClient.sendNetConnection(eventHandler.CorrelationId, eventHandler.Repo, event.IPAddress, event.Port, reverseLookUp, "", event.Timestamp, tool)
-			eventHandler.ProcessConnectionMap[cacheKey] = true
+	tool := Tool{}
+	image, digest := GetContainerByPid(event.Pid)
+	if image == "" {
+		if event.Exe != "" {
+			tool = *eventHandler.GetToolChain(event.PPid, event.Exe)
 		}
+
+	} else {
+		tool = Tool{Name: image, SHA256: digest}
 	}
 
-	eventHandler.netMutex.Unlock()
+	reverseLookUp := eventHandler.DNSProxy.GetReverseIPLookup(event.IPAddress)
+	eventHandler.EventSink.SendNetConnection(eventHandler.ApiClient, eventHandler.CorrelationId, eventHandler.Repo, event.IPAddress, event.Port, reverseLookUp, "", event.Timestamp, tool)
 }
 
 func (eventHandler *EventHandler) HandleEvent(event *Event) {
@@ -165,31 +187,13 @@ func (eventHandler *EventHandler) HandleEvent(event *Event) {
 	}
 }
 
-func GetContainerByPid(pid string) string {
-	cgroupPath := fmt.Sprintf("/proc/%s/cgroup", pid)
-	content, _ := ioutil.ReadFile(cgroupPath)
-
-	ctx := context.Background()
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		//panic(err)
-	}
-
-	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{})
-	if err != nil {
-		//panic(err)
-	}
-
-	for _, container := range containers {
-		json, _ := cli.ContainerInspect(ctx, container.ID)
-		if strings.Compare(pid, fmt.Sprintf("%d", json.State.Pid)) == 0 {
-			return container.Image
-		} else if strings.Contains(string(content), container.ID) {
-			return container.Image
-		}
-	}
-
-	return ""
+// GetContainerByPid returns the repo tag and image digest of the
+// container hosting pid, if any. Resolution goes through
+// ContainerResolver, which tries a cgroup v1/v2 string match (covering
+// rootless and cgroup v2 hosts) before falling back to runtime-specific
+// backends such as DockerWatcher's event-driven cache.
+func GetContainerByPid(pid string) (image, digest string) {
+	return getContainerResolver().Resolve(pid)
 }
 
 func getProgramChecksum(path string) (string, error) {
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultEventSinkQueueSize   = 10000
+	retryBaseDelay              = 500 * time.Millisecond
+	retryMaxDelay               = 30 * time.Second
+	defaultRetryLimit           = 5
+	defaultEventSinkMetricsAddr = ":9090"
+)
+
+// dropPolicy controls what EventSink does when its queue is full.
+type dropPolicy string
+
+const (
+	dropOldest dropPolicy = "drop-oldest"
+	blockSink  dropPolicy = "block"
+)
+
+// sinkEvent is a queued unit of work; send performs the actual
+// ApiClient call and is retried with backoff on failure.
+type sinkEvent struct {
+	send func() error
+}
+
+// EventSink decouples HandleEvent from ApiClient so a slow or
+// unreachable API never stalls the eBPF/ptrace event pump. Events are
+// queued onto a bounded channel and delivered by a pool of worker
+// goroutines with exponential backoff retries. Once an event is
+// queued, fileMutex/netMutex in EventHandler are no longer held for the
+// network call, only for the in-memory dedupe maps.
+type EventSink struct {
+	queue      chan sinkEvent
+	policy     dropPolicy
+	retryLimit int
+
+	dropped uint64
+	retried uint64
+}
+
+// NewEventSink starts the worker pool and returns the sink. Queue size,
+// worker count, retry limit, and drop policy are all configurable via
+// environment variables so operators can tune backpressure behavior the
+// same way the drone agent exposes DRONE_RETRY_LIMIT.
+func NewEventSink() *EventSink {
+	sink := &EventSink{
+		queue:      make(chan sinkEvent, envInt("EVENT_SINK_QUEUE_SIZE", defaultEventSinkQueueSize)),
+		policy:     dropPolicy(envString("EVENT_SINK_DROP_POLICY", string(dropOldest))),
+		retryLimit: envInt("EVENT_SINK_RETRY_LIMIT", defaultRetryLimit),
+	}
+
+	workers := envInt("EVENT_SINK_WORKERS", runtime.NumCPU())
+	for i := 0; i < workers; i++ {
+		go sink.worker()
+	}
+
+	metricsAddr := envString("EVENT_SINK_METRICS_ADDR", defaultEventSinkMetricsAddr)
+	go func() {
+		if err := sink.ServeMetrics(metricsAddr); err != nil {
+			WriteLog(fmt.Sprintf("event sink: metrics server stopped: %s", err))
+		}
+	}()
+
+	return sink
+}
+
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+
+	return parsed
+}
+
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// SendFileEvent queues a file event for delivery by ApiClient. fileType
+// stays one of the plain "Dependencies"/"Source Code" enum values;
+// ecosystem and pkg (e.g. "rubygems", "rack-2.2.3") are passed through as
+// their own fields rather than folded into fileType, and are empty for
+// event types that don't carry package info.
+func (sink *EventSink) SendFileEvent(apiClient *ApiClient, correlationId, repo, fileType, ecosystem, pkg string, timestamp int64, tool Tool) {
+	sink.enqueue(sinkEvent{
+		send: func() error {
+			return apiClient.sendFileEvent(correlationId, repo, fileType, ecosystem, pkg, timestamp, tool)
+		},
+	})
+}
+
+// SendNetConnection queues a network connection event for delivery by
+// ApiClient.
+func (sink *EventSink) SendNetConnection(apiClient *ApiClient, correlationId, repo, ipAddress, port, reverseLookUp, direction string, timestamp int64, tool Tool) {
+	sink.enqueue(sinkEvent{
+		send: func() error {
+			return apiClient.sendNetConnection(correlationId, repo, ipAddress, port, reverseLookUp, direction, timestamp, tool)
+		},
+	})
+}
+
+// enqueue applies the configured drop policy when the queue is full:
+// drop-oldest discards the head of the queue to make room for the new
+// event, block waits for a worker to free up a slot.
+//
+// Under concurrent producers the dequeue-then-enqueue pair below isn't
+// atomic, so dropped is a best-effort diagnostic counter, not an exact
+// count: a worker can drain the queue between the two selects, or
+// another producer can race the same slot. That's acceptable here
+// because dropped only feeds /metrics, not delivery correctness — the
+// queue itself never exceeds its configured capacity either way.
+func (sink *EventSink) enqueue(task sinkEvent) {
+	if sink.policy == blockSink {
+		sink.queue <- task
+		return
+	}
+
+	select {
+	case sink.queue <- task:
+	default:
+		select {
+		case <-sink.queue:
+			atomic.AddUint64(&sink.dropped, 1)
+		default:
+		}
+		select {
+		case sink.queue <- task:
+		default:
+			atomic.AddUint64(&sink.dropped, 1)
+		}
+	}
+}
+
+func (sink *EventSink) worker() {
+	for task := range sink.queue {
+		sink.deliver(task)
+	}
+}
+
+// deliver retries task.send with exponential backoff (base 500ms, cap
+// 30s, full jitter) up to retryLimit times after the first attempt
+// (retryLimit+1 attempts in all) before giving up.
+func (sink *EventSink) deliver(task sinkEvent) {
+	delay := retryBaseDelay
+
+	for attempt := 0; ; attempt++ {
+		err := task.send()
+		if err == nil {
+			return
+		}
+
+		if attempt >= sink.retryLimit {
+			WriteLog(fmt.Sprintf("event sink: dropping event after %d attempts: %s", attempt+1, err))
+			return
+		}
+
+		atomic.AddUint64(&sink.retried, 1)
+		time.Sleep(time.Duration(rand.Int63n(int64(delay))))
+
+		delay *= 2
+		if delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+}
+
+// ServeMetrics exposes queue depth and drop/retry counters on addr for
+// diagnostics. NewEventSink starts this automatically on
+// EVENT_SINK_METRICS_ADDR (default ":9090"); it is exported mainly so
+// tests can point it at an ephemeral port.
+func (sink *EventSink) ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "event_sink_queue_depth %d\n", len(sink.queue))
+		fmt.Fprintf(w, "event_sink_dropped_total %d\n", atomic.LoadUint64(&sink.dropped))
+		fmt.Fprintf(w, "event_sink_retried_total %d\n", atomic.LoadUint64(&sink.retried))
+	})
+
+	return http.ListenAndServe(addr, mux)
+}